@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+const defaultRecentCount = 5
+
+// commandHandler processes one subscriber command's arguments, replying
+// directly via sendTextMessage.
+type commandHandler func(ctx context.Context, client *firestore.Client, chatID int64, args []string) error
+
+// commandRouter is the table-driven dispatch for DM'd subscriber
+// commands — adding a command is a single registration here.
+var commandRouter = map[string]commandHandler{
+	"/start":       cmdStart,
+	"/unsubscribe": cmdUnsubscribe,
+	"/status":      cmdStatus,
+	"/mute":        cmdMute,
+	"/unmute":      cmdUnmute,
+	"/lang":        cmdLang,
+	"/recent":      cmdRecent,
+	"/stats":       cmdStats,
+}
+
+// translations holds the handful of user-facing strings formatMessage
+// and the help text need per locale. Missing locales/keys fall back to
+// English.
+var translations = map[string]map[string]string{
+	"en": {
+		"job_header": "📢 Job Post",
+		"channel":    "Channel",
+		"help":       "Unknown command. Try /mute, /unmute, /lang, /recent, /stats, /status, or /unsubscribe.",
+	},
+	"hi": {
+		"job_header": "📢 नई नौकरी",
+		"channel":    "चैनल",
+		"help":       "अज्ञात कमांड। /mute, /unmute, /lang, /recent, /stats, /status, या /unsubscribe आज़माएं।",
+	},
+}
+
+func translate(lang, key string) string {
+	if strs, ok := translations[lang]; ok {
+		if v, ok := strs[key]; ok {
+			return v
+		}
+	}
+	return translations["en"][key]
+}
+
+// handleSubscriberCommand dispatches a DM'd command to its registered
+// handler. Anything that isn't a registered command — including plain
+// conversational text or a caption-less photo — gets a localized help
+// reply rather than being treated as a job-post candidate; DMs to the
+// bot are never stored or forwarded.
+func handleSubscriberCommand(ctx context.Context, client *firestore.Client, msg *TelegramMessageRaw) {
+	text := strings.TrimSpace(msg.Text)
+
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		sendTextMessage(msg.Chat.ID, translate(subscriberLanguage(ctx, client, msg.Chat.ID), "help"))
+		return
+	}
+
+	name, args := fields[0], fields[1:]
+
+	handler, ok := commandRouter[name]
+	if !ok {
+		sendTextMessage(msg.Chat.ID, translate(subscriberLanguage(ctx, client, msg.Chat.ID), "help"))
+		return
+	}
+
+	if err := handler(ctx, client, msg.Chat.ID, args); err != nil {
+		log.Println("command error:", name, err)
+	}
+}
+
+func subscriberLanguage(ctx context.Context, client *firestore.Client, chatID int64) string {
+	sub, err := getSubscriber(ctx, client, chatID)
+	if err != nil {
+		return "en"
+	}
+	return subscriberLang(*sub)
+}
+
+func subscriberLang(sub Subscriber) string {
+	if sub.Language == "" {
+		return "en"
+	}
+	return sub.Language
+}
+
+func cmdStart(ctx context.Context, client *firestore.Client, chatID int64, args []string) error {
+	pin, err := storePendingSubscriber(ctx, client, chatID)
+	if err != nil {
+		sendTextMessage(chatID, "Sorry, something went wrong starting enrollment. Try again shortly.")
+		return err
+	}
+	sendTextMessage(chatID, fmt.Sprintf(
+		"Your enrollment PIN is %s. It expires in %d minutes — share it with the operator to finish signing up.",
+		pin, int(pinTTL.Minutes()),
+	))
+	return nil
+}
+
+func cmdUnsubscribe(ctx context.Context, client *firestore.Client, chatID int64, args []string) error {
+	if err := deactivateSubscriber(ctx, client, chatID); err != nil {
+		sendTextMessage(chatID, "You're not currently subscribed.")
+		return err
+	}
+	sendTextMessage(chatID, "You've been unsubscribed. Send /start to enroll again.")
+	return nil
+}
+
+func cmdStatus(ctx context.Context, client *firestore.Client, chatID int64, args []string) error {
+	sub, err := getSubscriber(ctx, client, chatID)
+	if err != nil {
+		sendTextMessage(chatID, "You're not enrolled yet. Send /start to begin.")
+		return err
+	}
+
+	state := "inactive"
+	if sub.Active {
+		state = "active"
+	}
+	name := sub.Name
+	if name == "" {
+		name = "unnamed"
+	}
+	sendTextMessage(chatID, fmt.Sprintf("Subscription: %s as %s (enrolled %s)", state, name, sub.EnrolledAt.Format(time.RFC822)))
+	return nil
+}
+
+func cmdMute(ctx context.Context, client *firestore.Client, chatID int64, args []string) error {
+	return setChannelMute(ctx, client, chatID, args, true, "Muted.")
+}
+
+func cmdUnmute(ctx context.Context, client *firestore.Client, chatID int64, args []string) error {
+	return setChannelMute(ctx, client, chatID, args, false, "Unmuted.")
+}
+
+func setChannelMute(ctx context.Context, client *firestore.Client, chatID int64, args []string, muted bool, confirmation string) error {
+	if len(args) == 0 {
+		sendTextMessage(chatID, "Usage: /mute <channel_id_or_title>")
+		return nil
+	}
+
+	channelID, err := resolveChannelID(ctx, client, args[0])
+	if err != nil {
+		sendTextMessage(chatID, "Couldn't find that channel.")
+		return err
+	}
+
+	var op interface{}
+	if muted {
+		op = firestore.ArrayUnion(channelID)
+	} else {
+		op = firestore.ArrayRemove(channelID)
+	}
+
+	_, err = client.Collection(subscribersCollection).
+		Doc(strconv.FormatInt(chatID, 10)).
+		Update(ctx, []firestore.Update{
+			{Path: "muted_channels", Value: op},
+		})
+	if err != nil {
+		sendTextMessage(chatID, "Couldn't update your muted channels.")
+		return err
+	}
+
+	sendTextMessage(chatID, confirmation)
+	return nil
+}
+
+// resolveChannelID accepts either a numeric channel ID or a channel
+// title, looking the latter up against the most recent message we've
+// stored for it.
+func resolveChannelID(ctx context.Context, client *firestore.Client, identifier string) (int64, error) {
+	if id, err := strconv.ParseInt(identifier, 10, 64); err == nil {
+		return id, nil
+	}
+
+	iter := client.Collection(messagesCollection).
+		Where("channel_name", "==", identifier).
+		OrderBy("received_at", firestore.Desc).
+		Limit(1).
+		Documents(ctx)
+
+	docs, err := iter.GetAll()
+	if err != nil {
+		return 0, err
+	}
+	if len(docs) == 0 {
+		return 0, fmt.Errorf("unknown channel %q", identifier)
+	}
+
+	var msg TelegramMessage
+	if err := docs[0].DataTo(&msg); err != nil {
+		return 0, err
+	}
+	return msg.ChannelID, nil
+}
+
+func cmdLang(ctx context.Context, client *firestore.Client, chatID int64, args []string) error {
+	if len(args) == 0 {
+		sendTextMessage(chatID, "Usage: /lang <code> (e.g. en, hi)")
+		return nil
+	}
+
+	lang := strings.ToLower(args[0])
+	_, err := client.Collection(subscribersCollection).
+		Doc(strconv.FormatInt(chatID, 10)).
+		Update(ctx, []firestore.Update{
+			{Path: "language", Value: lang},
+		})
+	if err != nil {
+		sendTextMessage(chatID, "Couldn't update your language.")
+		return err
+	}
+
+	sendTextMessage(chatID, fmt.Sprintf("Language set to %s.", lang))
+	return nil
+}
+
+func cmdRecent(ctx context.Context, client *firestore.Client, chatID int64, args []string) error {
+	n := defaultRecentCount
+	if len(args) > 0 {
+		if parsed, err := strconv.Atoi(args[0]); err == nil && parsed > 0 {
+			n = parsed
+		}
+	}
+
+	iter := client.Collection(messagesCollection).
+		Where("is_forwarded", "==", true).
+		OrderBy("processed_at", firestore.Desc).
+		Limit(n).
+		Documents(ctx)
+
+	docs, err := iter.GetAll()
+	if err != nil {
+		sendTextMessage(chatID, "Couldn't fetch recent posts.")
+		return err
+	}
+	if len(docs) == 0 {
+		sendTextMessage(chatID, "No forwarded posts yet.")
+		return nil
+	}
+
+	lang := subscriberLanguage(ctx, client, chatID)
+	for i := len(docs) - 1; i >= 0; i-- {
+		var msg TelegramMessage
+		if err := docs[i].DataTo(&msg); err != nil {
+			continue
+		}
+		sendTextMessage(chatID, formatMessage(msg, lang))
+	}
+	return nil
+}
+
+func cmdStats(ctx context.Context, client *firestore.Client, chatID int64, args []string) error {
+	since := time.Now().Add(-24 * time.Hour)
+
+	processed, err := countDocs(ctx, client.Collection(messagesCollection).
+		Where("processed_at", ">=", since))
+	if err != nil {
+		sendTextMessage(chatID, "Couldn't compute stats.")
+		return err
+	}
+
+	relevant, err := countDocs(ctx, client.Collection(messagesCollection).
+		Where("processed_at", ">=", since).
+		Where("is_relevant", "==", true))
+	if err != nil {
+		sendTextMessage(chatID, "Couldn't compute stats.")
+		return err
+	}
+
+	forwarded, err := countDocs(ctx, client.Collection(messagesCollection).
+		Where("processed_at", ">=", since).
+		Where("is_forwarded", "==", true))
+	if err != nil {
+		sendTextMessage(chatID, "Couldn't compute stats.")
+		return err
+	}
+
+	sendTextMessage(chatID, fmt.Sprintf(
+		"Last 24h — processed: %d, relevant: %d, forwarded: %d",
+		processed, relevant, forwarded,
+	))
+	return nil
+}
+
+func countDocs(ctx context.Context, q firestore.Query) (int, error) {
+	iter := q.Documents(ctx)
+	defer iter.Stop()
+
+	count := 0
+	for {
+		_, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, nil
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
-	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"math/bits"
 	"net/http"
 	"os"
 	"regexp"
@@ -16,9 +19,33 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+
+	"github.com/Vishu-007/Tele-bot/senderPool"
+)
+
+const (
+	messagesCollection           = "telegram_messages"
+	subscribersCollection        = "subscribers"
+	subscribersPendingCollection = "subscribers_pending"
+	sendAttemptsCollection       = "send_attempts"
+	pinTTL                       = 10 * time.Minute
+	pinAlphabet                  = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	pinLength                    = 6
+
+	simHashBits         = 64
+	simHashNGram        = 3
+	simHashThreshold    = 3 // max Hamming distance considered a near-duplicate
+	simHashLookbackDays = 7
+
+	backlogLookbackDays = 7
 )
 
-const messagesCollection = "telegram_messages"
+// pool routes outbound sendMessage/forwardMessage calls across the
+// configured bot token(s); it is initialized in main() before the server
+// starts. The webhook and polling calls in poll.go (setWebhook,
+// deleteWebhook, getUpdates) bypass it and use the first token directly.
+var pool *senderPool.Pool
 
 type TelegramMessage struct {
 	ChannelID        int64     `firestore:"channel_id"`
@@ -35,12 +62,67 @@ type TelegramMessage struct {
 
 	JobFingerprint *string `firestore:"job_fingerprint"`
 	IsForwarded    bool    `firestore:"is_forwarded"`
+	DeliveredTo    []int64 `firestore:"delivered_to"`
+
+	// SimFingerprint is a 64-bit SimHash of the normalized text, used to
+	// catch reworded/reposted near-duplicates that the exact SHA-256
+	// JobFingerprint misses. The sim_band_* fields split it into four
+	// 16-bit bands so candidates can be found via equality queries
+	// instead of scanning every document.
+	SimFingerprint uint64 `firestore:"sim_fingerprint"`
+	SimBand0       uint16 `firestore:"sim_band_0"`
+	SimBand1       uint16 `firestore:"sim_band_1"`
+	SimBand2       uint16 `firestore:"sim_band_2"`
+	SimBand3       uint16 `firestore:"sim_band_3"`
+}
+
+// Subscriber is a Telegram user (or chat) enrolled to receive forwarded
+// job posts. Enrollment happens via PIN (see storePendingSubscriber /
+// promoteSubscriber) rather than a hard-coded chat ID.
+type Subscriber struct {
+	ChatID        int64     `firestore:"chat_id"`
+	Name          string    `firestore:"name"`
+	EnrolledAt    time.Time `firestore:"enrolled_at"`
+	Language      string    `firestore:"language"`
+	MutedChannels []int64   `firestore:"muted_channels"`
+	Active        bool      `firestore:"active"`
+}
+
+// PendingSubscriber is a short-lived enrollment request created when a
+// user DMs the bot with /start. It is promoted to a Subscriber once an
+// operator confirms the PIN via the /enroll endpoint, or it expires.
+type PendingSubscriber struct {
+	ChatID    int64     `firestore:"chat_id"`
+	PIN       string    `firestore:"pin"`
+	CreatedAt time.Time `firestore:"created_at"`
+	ExpiresAt time.Time `firestore:"expires_at"`
+}
+
+// SendAttempt tracks a delivery that failed (e.g. rate limited, network
+// error) so workerHandler can retry it on a later tick instead of
+// losing it.
+type SendAttempt struct {
+	DocID            string    `firestore:"doc_id"`
+	SubscriberChatID int64     `firestore:"subscriber_chat_id"`
+	Attempts         int       `firestore:"attempts"`
+	NextRetryAt      time.Time `firestore:"next_retry_at"`
+}
+
+// EditHistoryEntry records a single edit of a source post, stored in the
+// edit_history subcollection of its telegram_messages doc so the worker
+// (and operators) can reason about retractions.
+type EditHistoryEntry struct {
+	PreviousFingerprint *string   `firestore:"previous_fingerprint"`
+	NewFingerprint      string    `firestore:"new_fingerprint"`
+	EditedAt            time.Time `firestore:"edited_at"`
 }
 
 type TelegramUpdate struct {
-	UpdateID    int                 `json:"update_id"`
-	Message     *TelegramMessageRaw `json:"message,omitempty"`
-	ChannelPost *TelegramMessageRaw `json:"channel_post,omitempty"`
+	UpdateID          int                 `json:"update_id"`
+	Message           *TelegramMessageRaw `json:"message,omitempty"`
+	ChannelPost       *TelegramMessageRaw `json:"channel_post,omitempty"`
+	EditedMessage     *TelegramMessageRaw `json:"edited_message,omitempty"`
+	EditedChannelPost *TelegramMessageRaw `json:"edited_channel_post,omitempty"`
 }
 
 type Photo struct {
@@ -129,15 +211,22 @@ func updateProcessingResult(
 	docID string,
 	isRelevant bool,
 	fingerprint string,
+	simHash uint64,
 ) error {
 
 	now := time.Now()
+	bands := simHashBands(simHash)
 
 	updates := []firestore.Update{
 		{Path: "is_processed", Value: true},
 		{Path: "is_relevant", Value: isRelevant},
 		{Path: "processed_at", Value: now},
 		{Path: "job_fingerprint", Value: fingerprint},
+		{Path: "sim_fingerprint", Value: simHash},
+		{Path: "sim_band_0", Value: bands[0]},
+		{Path: "sim_band_1", Value: bands[1]},
+		{Path: "sim_band_2", Value: bands[2]},
+		{Path: "sim_band_3", Value: bands[3]},
 	}
 
 	_, err := client.Collection(messagesCollection).
@@ -172,6 +261,264 @@ func isFingerprintForwarded(ctx context.Context, client *firestore.Client, finge
 	return len(docs) > 0, nil
 }
 
+// isNearDuplicate checks whether simHash is within simHashThreshold
+// Hamming distance of any message forwarded in the last
+// simHashLookbackDays days. Each band is queried separately (candidates
+// are unioned) since Firestore can't do a bitwise-distance query
+// directly — the exact Hamming comparison happens in memory afterward.
+func isNearDuplicate(ctx context.Context, client *firestore.Client, simHash uint64) (bool, error) {
+	bands := simHashBands(simHash)
+	since := time.Now().AddDate(0, 0, -simHashLookbackDays)
+
+	seen := make(map[string]struct{})
+
+	for i, band := range bands {
+		field := fmt.Sprintf("sim_band_%d", i)
+
+		iter := client.Collection(messagesCollection).
+			Where(field, "==", band).
+			Where("is_forwarded", "==", true).
+			Where("processed_at", ">=", since).
+			Documents(ctx)
+
+		docs, err := iter.GetAll()
+		if err != nil {
+			return false, err
+		}
+
+		for _, doc := range docs {
+			if _, dup := seen[doc.Ref.ID]; dup {
+				continue
+			}
+			seen[doc.Ref.ID] = struct{}{}
+
+			var candidate TelegramMessage
+			if err := doc.DataTo(&candidate); err != nil {
+				continue
+			}
+			if hammingDistance(simHash, candidate.SimFingerprint) <= simHashThreshold {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func markDelivered(ctx context.Context, client *firestore.Client, docID string, subscriberChatID int64) error {
+	_, err := client.Collection(messagesCollection).
+		Doc(docID).
+		Update(ctx, []firestore.Update{
+			{Path: "delivered_to", Value: firestore.ArrayUnion(subscriberChatID)},
+		})
+
+	return err
+}
+
+func generatePIN() (string, error) {
+	b := make([]byte, pinLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	pin := make([]byte, pinLength)
+	for i, v := range b {
+		pin[i] = pinAlphabet[int(v)%len(pinAlphabet)]
+	}
+
+	return string(pin), nil
+}
+
+func storePendingSubscriber(ctx context.Context, client *firestore.Client, chatID int64) (string, error) {
+	pin, err := generatePIN()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	pending := PendingSubscriber{
+		ChatID:    chatID,
+		PIN:       pin,
+		CreatedAt: now,
+		ExpiresAt: now.Add(pinTTL),
+	}
+
+	_, err = client.Collection(subscribersPendingCollection).
+		Doc(strconv.FormatInt(chatID, 10)).
+		Set(ctx, pending)
+	if err != nil {
+		return "", err
+	}
+
+	return pin, nil
+}
+
+// promoteSubscriber confirms a pending enrollment by PIN and creates (or
+// reactivates) the corresponding Subscriber. It is called from the
+// /enroll HTTP endpoint, which an operator hits out-of-band after the
+// user reports their PIN. If sendBacklog is set, the new subscriber is
+// also sent any posts forwarded in the last backlogLookbackDays that
+// delivered_to shows they haven't received yet (see deliverBacklog).
+func promoteSubscriber(ctx context.Context, client *firestore.Client, pin string, name string, sendBacklog bool) error {
+	iter := client.Collection(subscribersPendingCollection).
+		Where("pin", "==", pin).
+		Limit(1).
+		Documents(ctx)
+
+	docs, err := iter.GetAll()
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		return fmt.Errorf("no pending subscriber for pin %q", pin)
+	}
+
+	var pending PendingSubscriber
+	if err := docs[0].DataTo(&pending); err != nil {
+		return err
+	}
+
+	if time.Now().After(pending.ExpiresAt) {
+		docs[0].Ref.Delete(ctx)
+		return fmt.Errorf("pin %q expired", pin)
+	}
+
+	subscriber := Subscriber{
+		ChatID:        pending.ChatID,
+		Name:          name,
+		EnrolledAt:    time.Now(),
+		Language:      "en",
+		MutedChannels: []int64{},
+		Active:        true,
+	}
+
+	_, err = client.Collection(subscribersCollection).
+		Doc(strconv.FormatInt(pending.ChatID, 10)).
+		Set(ctx, subscriber)
+	if err != nil {
+		return err
+	}
+
+	if _, err := docs[0].Ref.Delete(ctx); err != nil {
+		return err
+	}
+
+	if sendBacklog {
+		if err := deliverBacklog(ctx, client, subscriber); err != nil {
+			log.Println("deliver backlog error:", err)
+		}
+	}
+
+	return nil
+}
+
+// deliverBacklog sends sub any post forwarded in the last
+// backlogLookbackDays whose delivered_to doesn't already list their
+// chat ID — e.g. posts forwarded before they enrolled. Muted channels
+// are still respected, and each send is recorded via markDelivered so a
+// later backlog delivery (or a normal fan-out retry) doesn't repeat it.
+func deliverBacklog(ctx context.Context, client *firestore.Client, sub Subscriber) error {
+	since := time.Now().AddDate(0, 0, -backlogLookbackDays)
+
+	iter := client.Collection(messagesCollection).
+		Where("is_forwarded", "==", true).
+		Where("processed_at", ">=", since).
+		Documents(ctx)
+
+	docs, err := iter.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range docs {
+		var msg TelegramMessage
+		if err := doc.DataTo(&msg); err != nil {
+			continue
+		}
+		if subscriberHasMuted(sub, msg.ChannelID) || deliveredTo(msg.DeliveredTo, sub.ChatID) {
+			continue
+		}
+
+		if err := sendTextMessage(sub.ChatID, formatMessage(msg, subscriberLang(sub))); err != nil {
+			log.Println("backlog send error:", err)
+			continue
+		}
+		markDelivered(ctx, client, doc.Ref.ID, sub.ChatID)
+	}
+
+	return nil
+}
+
+func deliveredTo(chatIDs []int64, chatID int64) bool {
+	for _, id := range chatIDs {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+func deactivateSubscriber(ctx context.Context, client *firestore.Client, chatID int64) error {
+	_, err := client.Collection(subscribersCollection).
+		Doc(strconv.FormatInt(chatID, 10)).
+		Update(ctx, []firestore.Update{
+			{Path: "active", Value: false},
+		})
+
+	return err
+}
+
+func getSubscriber(ctx context.Context, client *firestore.Client, chatID int64) (*Subscriber, error) {
+	doc, err := client.Collection(subscribersCollection).
+		Doc(strconv.FormatInt(chatID, 10)).
+		Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var sub Subscriber
+	if err := doc.DataTo(&sub); err != nil {
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+func fetchActiveSubscribers(ctx context.Context, client *firestore.Client) ([]Subscriber, error) {
+	iter := client.Collection(subscribersCollection).
+		Where("active", "==", true).
+		Documents(ctx)
+	defer iter.Stop()
+
+	var subs []Subscriber
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var sub Subscriber
+		if err := doc.DataTo(&sub); err != nil {
+			continue
+		}
+		subs = append(subs, sub)
+	}
+
+	return subs, nil
+}
+
+func subscriberHasMuted(sub Subscriber, channelID int64) bool {
+	for _, id := range sub.MutedChannels {
+		if id == channelID {
+			return true
+		}
+	}
+	return false
+}
+
 func normalizeText(text string) string {
 	t := strings.ToLower(text)
 
@@ -195,6 +542,64 @@ func computeFingerprint(messageText string) string {
 	return hex.EncodeToString(hash[:])
 }
 
+// computeSimHash builds a 64-bit SimHash over 3-gram word shingles of
+// the normalized text, so reworded or re-linked reposts land close in
+// Hamming distance even though their exact SHA-256 fingerprint differs.
+func computeSimHash(text string) uint64 {
+	words := strings.Fields(normalizeText(text))
+	if len(words) == 0 {
+		return 0
+	}
+
+	var ngrams []string
+	if len(words) < simHashNGram {
+		ngrams = []string{strings.Join(words, " ")}
+	} else {
+		for i := 0; i+simHashNGram <= len(words); i++ {
+			ngrams = append(ngrams, strings.Join(words[i:i+simHashNGram], " "))
+		}
+	}
+
+	var weights [simHashBits]int
+	for _, ng := range ngrams {
+		h := fnv.New64a()
+		h.Write([]byte(ng))
+		sum := h.Sum64()
+
+		for bit := 0; bit < simHashBits; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var simHash uint64
+	for bit := 0; bit < simHashBits; bit++ {
+		if weights[bit] > 0 {
+			simHash |= 1 << uint(bit)
+		}
+	}
+
+	return simHash
+}
+
+func hammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// simHashBands splits a 64-bit SimHash into four 16-bit bands so
+// candidates can be narrowed down with equality queries (sim_band_0..3)
+// before the exact Hamming-distance comparison happens in memory.
+func simHashBands(hash uint64) [4]uint16 {
+	var bands [4]uint16
+	for i := range bands {
+		bands[i] = uint16(hash >> uint(i*16))
+	}
+	return bands
+}
+
 func containsAny(text string, patterns []string) bool {
 	for _, p := range patterns {
 		if strings.Contains(text, p) {
@@ -256,15 +661,41 @@ func telegramWebhookHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Handle both message & channel_post
+	client, err := getFirestoreClient(ctx)
+	if err != nil {
+		// 🔴 IMPORTANT: log, but ACK Telegram
+		log.Println("Firestore client error:", err)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	defer client.Close()
+
+	handleUpdate(ctx, client, update)
+
+	// ✅ Final ACK — exactly once
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleUpdate processes a single TelegramUpdate and is shared by both
+// the webhook handler and the long-polling loop, so the two modes
+// behave identically.
+func handleUpdate(ctx context.Context, client *firestore.Client, update TelegramUpdate) {
+	// Handle message, channel_post, and their edited counterparts
 	var msg *TelegramMessageRaw
-	if update.Message != nil {
+	isEdit := false
+	switch {
+	case update.Message != nil:
 		msg = update.Message
-	} else if update.ChannelPost != nil {
+	case update.ChannelPost != nil:
 		msg = update.ChannelPost
-	} else {
+	case update.EditedMessage != nil:
+		msg = update.EditedMessage
+		isEdit = true
+	case update.EditedChannelPost != nil:
+		msg = update.EditedChannelPost
+		isEdit = true
+	default:
 		// Not a message we care about
-		w.WriteHeader(http.StatusOK)
 		return
 	}
 
@@ -272,21 +703,24 @@ func telegramWebhookHandler(w http.ResponseWriter, r *http.Request) {
 	hasText := msg.Text != "" || msg.Caption != ""
 
 	if !hasText && !hasMedia {
-		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	// DMs to the bot are subscriber traffic (commands or otherwise),
+	// never job-post candidates — handle and stop here regardless of
+	// whether the text matches a registered command.
+	if !isEdit && msg.Chat.Type == "private" {
+		handleSubscriberCommand(ctx, client, msg)
 		return
 	}
 
 	// Build Firestore document ID
 	docID := fmt.Sprintf("%d_%d", msg.Chat.ID, msg.MessageID)
 
-	client, err := getFirestoreClient(ctx)
-	if err != nil {
-		// 🔴 IMPORTANT: log, but ACK Telegram
-		log.Println("Firestore client error:", err)
-		w.WriteHeader(http.StatusOK)
+	if isEdit {
+		handleEditedMessage(ctx, client, docID, msg)
 		return
 	}
-	defer client.Close()
 
 	firestoreMsg := TelegramMessage{
 		ChannelID:        msg.Chat.ID,
@@ -306,73 +740,161 @@ func telegramWebhookHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Idempotent upsert
 	if err := storeMessage(ctx, client, docID, firestoreMsg); err != nil {
-		// 🔴 IMPORTANT: log, but ACK Telegram
 		log.Println("Firestore write error:", err)
-		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// handleEditedMessage reacts to a source channel editing a previously
+// seen post (e.g. fixing a typo, or marking a role as filled). It
+// overwrites the stored text, resets processing state so processOne
+// re-evaluates relevance and recomputes the fingerprint, and — if the
+// post had already been forwarded under a different fingerprint — tells
+// subscribers about the update rather than re-forwarding it as new.
+func handleEditedMessage(ctx context.Context, client *firestore.Client, docID string, msg *TelegramMessageRaw) {
+	ref := client.Collection(messagesCollection).Doc(docID)
+
+	snap, err := ref.Get(ctx)
+	if err != nil {
+		// Nothing to update — we never stored the original post
 		return
 	}
 
-	// ✅ Final ACK — exactly once
-	w.WriteHeader(http.StatusOK)
-}
+	var existing TelegramMessage
+	if err := snap.DataTo(&existing); err != nil {
+		return
+	}
 
-func telegramAPIURL(method string) string {
-	return fmt.Sprintf(
-		"https://api.telegram.org/bot%s/%s",
-		os.Getenv("BOT_TOKEN"),
-		method,
-	)
-}
+	newText := msg.Text
+	if newText == "" {
+		newText = msg.Caption
+	}
+	newFingerprint := computeFingerprint(newText)
 
-func forwardMessage(chatID int64, fromChatID int64, messageID int) error {
-	payload := map[string]interface{}{
-		"chat_id":      chatID,
-		"from_chat_id": fromChatID,
-		"message_id":   messageID,
+	updates := []firestore.Update{
+		{Path: "message_text", Value: newText},
+		{Path: "message_timestamp", Value: time.Unix(msg.Date, 0)},
+		{Path: "is_processed", Value: false},
+		{Path: "is_relevant", Value: nil},
+		{Path: "job_fingerprint", Value: nil},
+	}
+	if _, err := ref.Update(ctx, updates); err != nil {
+		log.Println("edit update error:", err)
+		return
 	}
 
-	body, _ := json.Marshal(payload)
+	if _, _, err := ref.Collection("edit_history").Add(ctx, EditHistoryEntry{
+		PreviousFingerprint: existing.JobFingerprint,
+		NewFingerprint:      newFingerprint,
+		EditedAt:            time.Now(),
+	}); err != nil {
+		log.Println("edit history error:", err)
+	}
 
-	resp, err := http.Post(
-		telegramAPIURL("forwardMessage"),
-		"application/json",
-		bytes.NewBuffer(body),
-	)
+	changedAfterForward := existing.JobFingerprint != nil &&
+		*existing.JobFingerprint != newFingerprint &&
+		existing.IsForwarded
+	if changedAfterForward {
+		notifyEditedPost(ctx, client, existing, newText)
+	}
+}
+
+// notifyEditedPost tells subscribers about a retraction/update to a post
+// they've already received, instead of silently re-forwarding it.
+func notifyEditedPost(ctx context.Context, client *firestore.Client, msg TelegramMessage, newText string) {
+	subs, err := fetchActiveSubscribers(ctx, client)
 	if err != nil {
-		return err
+		log.Println("fetch subscribers error:", err)
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("telegram forward failed: %d", resp.StatusCode)
+	text := fmt.Sprintf("🔄 Updated job post from %s: %s", msg.ChannelName, newText)
+	for _, sub := range subs {
+		if subscriberHasMuted(sub, msg.ChannelID) {
+			continue
+		}
+		sendTextMessage(sub.ChatID, text)
 	}
+}
 
-	return nil
+// isOperatorAuthorized checks the ENROLL_OPERATOR_SECRET shared secret
+// against the X-Operator-Secret header (or a "secret" query param, for
+// curl-from-a-terminal convenience). The PIN alone is not sufficient to
+// call /enroll: it's handed straight back to the requesting user by
+// cmdStart, so without this gate they could self-promote.
+func isOperatorAuthorized(r *http.Request) bool {
+	want := os.Getenv("ENROLL_OPERATOR_SECRET")
+	if want == "" {
+		// Misconfiguration — fail closed rather than silently open the route.
+		return false
+	}
+
+	got := r.Header.Get("X-Operator-Secret")
+	if got == "" {
+		got = r.URL.Query().Get("secret")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
 }
 
-func sendTextMessage(chatID int64, text string) error {
-	payload := map[string]interface{}{
-		"chat_id": chatID,
-		"text":    text,
+// enrollHandler lets an operator confirm a user's PIN out-of-band,
+// promoting the pending signup into an active subscriber. Requires the
+// ENROLL_OPERATOR_SECRET shared secret (see isOperatorAuthorized). Pass
+// backlog=1 to also send the new subscriber recent posts they missed
+// (see deliverBacklog).
+// e.g. GET /enroll?pin=XXXXXX&name=Jane&secret=...&backlog=1
+func enrollHandler(w http.ResponseWriter, r *http.Request) {
+	if !isOperatorAuthorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
 	}
 
-	body, _ := json.Marshal(payload)
+	pin := r.URL.Query().Get("pin")
+	name := r.URL.Query().Get("name")
+	if pin == "" {
+		http.Error(w, "missing pin", http.StatusBadRequest)
+		return
+	}
+	sendBacklog := r.URL.Query().Get("backlog") == "1"
 
-	resp, err := http.Post(
-		telegramAPIURL("sendMessage"),
-		"application/json",
-		bytes.NewBuffer(body),
-	)
+	ctx := context.Background()
+	client, err := getFirestoreClient(ctx)
 	if err != nil {
-		return err
+		http.Error(w, "firestore unavailable", http.StatusInternalServerError)
+		return
 	}
-	defer resp.Body.Close()
+	defer client.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("telegram send failed: %d", resp.StatusCode)
+	if err := promoteSubscriber(ctx, client, pin, name, sendBacklog); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	return nil
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("enrolled"))
+}
+
+// botTokens reads the comma-separated BOT_TOKENS env var, falling back
+// to the single BOT_TOKEN for backwards compatibility.
+func botTokens() []string {
+	if raw := os.Getenv("BOT_TOKENS"); raw != "" {
+		return strings.Split(raw, ",")
+	}
+	return []string{os.Getenv("BOT_TOKEN")}
+}
+
+func forwardMessage(chatID int64, fromChatID int64, messageID int) error {
+	return pool.Send("forwardMessage", chatID, map[string]interface{}{
+		"chat_id":      chatID,
+		"from_chat_id": fromChatID,
+		"message_id":   messageID,
+	})
+}
+
+func sendTextMessage(chatID int64, text string) error {
+	return pool.Send("sendMessage", chatID, map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	})
 }
 
 func workerHandler(w http.ResponseWriter, r *http.Request) {
@@ -408,11 +930,21 @@ func workerHandler(w http.ResponseWriter, r *http.Request) {
 		processOne(ctx, client, doc)
 	}
 
+	// Retry deliveries that failed on a prior tick (rate limited, etc.)
+	retryPendingSends(ctx, client)
+
 	// 3️⃣ Always write a response body
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("processed"))
 }
 
+// metricsHandler exposes senderPool counters (in-flight sends, 429s,
+// per-token send counts) for operators.
+func metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pool.Snapshot())
+}
+
 func processOne(
 	ctx context.Context,
 	client *firestore.Client,
@@ -424,8 +956,9 @@ func processOne(
 		return
 	}
 
-	// Compute fingerprint
+	// Compute fingerprints (exact + near-duplicate)
 	fingerprint := computeFingerprint(msg.MessageText)
+	simHash := computeSimHash(msg.MessageText)
 
 	// Apply relevance rules
 	relevant := isRelevant(msg.MessageText)
@@ -437,6 +970,7 @@ func processOne(
 		doc.Ref.ID,
 		relevant,
 		fingerprint,
+		simHash,
 	)
 
 	if !relevant {
@@ -449,28 +983,128 @@ func processOne(
 		return
 	}
 
-	// Send message to personal chat
-	chatID := mustGetPersonalChatID()
-	sendTextMessage(chatID, formatMessage(msg))
+	nearDup, err := isNearDuplicate(ctx, client, simHash)
+	if err != nil {
+		log.Println("near-duplicate check error:", err)
+	} else if nearDup {
+		return
+	}
+
+	// Fan out to every active, non-muted subscriber
+	subs, err := fetchActiveSubscribers(ctx, client)
+	if err != nil {
+		log.Println("fetch subscribers error:", err)
+		return
+	}
 
-	// Mark forwarded
-	markForwarded(ctx, client, doc.Ref.ID)
+	delivered := 0
+	for _, sub := range subs {
+		if subscriberHasMuted(sub, msg.ChannelID) {
+			continue
+		}
+		if err := sendTextMessage(sub.ChatID, formatMessage(msg, subscriberLang(sub))); err != nil {
+			log.Println("send error:", err)
+			recordSendAttempt(ctx, client, doc.Ref.ID, sub.ChatID)
+			continue
+		}
+		markDelivered(ctx, client, doc.Ref.ID, sub.ChatID)
+		delivered++
+	}
+
+	// Only mark forwarded once at least one subscriber actually got it
+	if delivered > 0 {
+		markForwarded(ctx, client, doc.Ref.ID)
+	}
 }
 
-func formatMessage(msg TelegramMessage) string {
+// recordSendAttempt persists (or bumps) a failed delivery so it can be
+// retried on a later workerHandler tick rather than silently dropped.
+// The retry delay backs off linearly with the attempt count.
+func recordSendAttempt(ctx context.Context, client *firestore.Client, docID string, subscriberChatID int64) {
+	id := fmt.Sprintf("%s_%d", docID, subscriberChatID)
+	ref := client.Collection(sendAttemptsCollection).Doc(id)
+
+	attempts := 1
+	if snap, err := ref.Get(ctx); err == nil {
+		var existing SendAttempt
+		if err := snap.DataTo(&existing); err == nil {
+			attempts = existing.Attempts + 1
+		}
+	}
+
+	_, err := ref.Set(ctx, SendAttempt{
+		DocID:            docID,
+		SubscriberChatID: subscriberChatID,
+		Attempts:         attempts,
+		NextRetryAt:      time.Now().Add(time.Duration(attempts) * time.Minute),
+	})
+	if err != nil {
+		log.Println("record send attempt error:", err)
+	}
+}
+
+// retryPendingSends re-attempts deliveries recorded by recordSendAttempt
+// whose backoff has elapsed.
+func retryPendingSends(ctx context.Context, client *firestore.Client) {
+	iter := client.Collection(sendAttemptsCollection).
+		Where("next_retry_at", "<=", time.Now()).
+		Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			log.Println("retry pending sends error:", err)
+			return
+		}
+
+		var attempt SendAttempt
+		if err := doc.DataTo(&attempt); err != nil {
+			continue
+		}
+
+		msgSnap, err := client.Collection(messagesCollection).Doc(attempt.DocID).Get(ctx)
+		if err != nil {
+			// Source doc is gone — nothing left to retry
+			doc.Ref.Delete(ctx)
+			continue
+		}
+
+		var msg TelegramMessage
+		if err := msgSnap.DataTo(&msg); err != nil {
+			continue
+		}
+
+		// Already delivered some other way (e.g. backlog delivery)
+		if deliveredTo(msg.DeliveredTo, attempt.SubscriberChatID) {
+			doc.Ref.Delete(ctx)
+			continue
+		}
+
+		lang := subscriberLanguage(ctx, client, attempt.SubscriberChatID)
+		if err := sendTextMessage(attempt.SubscriberChatID, formatMessage(msg, lang)); err != nil {
+			recordSendAttempt(ctx, client, attempt.DocID, attempt.SubscriberChatID)
+			continue
+		}
+
+		markDelivered(ctx, client, attempt.DocID, attempt.SubscriberChatID)
+		doc.Ref.Delete(ctx)
+	}
+}
+
+func formatMessage(msg TelegramMessage, lang string) string {
 	return fmt.Sprintf(
-		"📢 Job Post\n\nChannel: %s\n\n%s",
+		"%s\n\n%s: %s\n\n%s",
+		translate(lang, "job_header"),
+		translate(lang, "channel"),
 		msg.ChannelName,
 		msg.MessageText,
 	)
 }
 
-func mustGetPersonalChatID() int64 {
-	idStr := os.Getenv("PERSONAL_CHAT_ID")
-	id, _ := strconv.ParseInt(idStr, 10, 64)
-	return id
-}
-
 func main() {
 	// Cloud Run provides PORT env variable
 	port := os.Getenv("PORT")
@@ -478,14 +1112,37 @@ func main() {
 		port = "8080" // local fallback
 	}
 
-	mux := http.NewServeMux()
+	pool = senderPool.New(botTokens())
 
-	// Webhook endpoint (Telegram calls this)
-	mux.HandleFunc("/webhook", telegramWebhookHandler)
+	mux := http.NewServeMux()
 
 	// Worker endpoint (Cloud Scheduler / manual trigger)
 	mux.HandleFunc("/worker", workerHandler)
 
+	// Operator endpoint to confirm a subscriber's enrollment PIN
+	mux.HandleFunc("/enroll", enrollHandler)
+
+	// senderPool metrics (in-flight, 429s, per-token send counts)
+	mux.HandleFunc("/metrics", metricsHandler)
+
+	// MODE=polling is a webhook-free fallback for local dev, self-hosting
+	// behind NAT, or Cloud Run cold starts that would otherwise drop the
+	// first few updates. Defaults to webhook mode.
+	if os.Getenv("MODE") == "polling" {
+		if err := deleteWebhook(); err != nil {
+			log.Println("deleteWebhook error:", err)
+		}
+		go runPollingLoop(context.Background())
+	} else {
+		mux.HandleFunc("/webhook", telegramWebhookHandler)
+
+		if webhookURL := os.Getenv("WEBHOOK_URL"); webhookURL != "" {
+			if err := setWebhook(webhookURL); err != nil {
+				log.Println("setWebhook error:", err)
+			}
+		}
+	}
+
 	log.Printf("Starting server on port %s", port)
 
 	if err := http.ListenAndServe(":"+port, mux); err != nil {
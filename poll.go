@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+const (
+	pollStateCollection = "bot_state"
+	pollStateDoc        = "polling"
+	pollTimeoutSeconds  = 50
+)
+
+// pollState is a Firestore singleton recording the last update_id we've
+// acknowledged, so a restart in polling mode resumes cleanly instead of
+// reprocessing or losing updates.
+type pollState struct {
+	LastUpdateID int `firestore:"last_update_id"`
+}
+
+func primaryBotToken() string {
+	tokens := botTokens()
+	if len(tokens) == 0 {
+		return ""
+	}
+	return tokens[0]
+}
+
+func telegramAdminURL(method string) string {
+	return fmt.Sprintf("https://api.telegram.org/bot%s/%s", primaryBotToken(), method)
+}
+
+func postAdmin(method string, payload map[string]interface{}) error {
+	body, _ := json.Marshal(payload)
+
+	resp, err := http.Post(telegramAdminURL(method), "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("telegram %s failed: %d", method, resp.StatusCode)
+	}
+	return nil
+}
+
+func setWebhook(url string) error {
+	return postAdmin("setWebhook", map[string]interface{}{"url": url})
+}
+
+func deleteWebhook() error {
+	return postAdmin("deleteWebhook", map[string]interface{}{})
+}
+
+func getUpdates(offset, timeoutSeconds int) ([]TelegramUpdate, error) {
+	url := fmt.Sprintf("%s?offset=%d&timeout=%d", telegramAdminURL("getUpdates"), offset, timeoutSeconds)
+
+	httpClient := &http.Client{Timeout: time.Duration(timeoutSeconds+10) * time.Second}
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var parsed struct {
+		OK     bool             `json:"ok"`
+		Result []TelegramUpdate `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, err
+	}
+	if !parsed.OK {
+		return nil, fmt.Errorf("telegram getUpdates returned ok=false")
+	}
+
+	return parsed.Result, nil
+}
+
+func loadPollOffset(ctx context.Context, client *firestore.Client) int {
+	doc, err := client.Collection(pollStateCollection).Doc(pollStateDoc).Get(ctx)
+	if err != nil {
+		return 0
+	}
+
+	var state pollState
+	if err := doc.DataTo(&state); err != nil {
+		return 0
+	}
+	return state.LastUpdateID
+}
+
+func savePollOffset(ctx context.Context, client *firestore.Client, updateID int) {
+	_, err := client.Collection(pollStateCollection).Doc(pollStateDoc).Set(ctx, pollState{LastUpdateID: updateID})
+	if err != nil {
+		log.Println("save poll offset error:", err)
+	}
+}
+
+// runPollingLoop continuously long-polls getUpdates and feeds each
+// update through the same handleUpdate path the webhook uses, so both
+// transports behave identically. It's meant to run as a single
+// background goroutine for the lifetime of the process.
+func runPollingLoop(ctx context.Context) {
+	client, err := getFirestoreClient(ctx)
+	if err != nil {
+		log.Fatalf("polling: firestore client error: %v", err)
+	}
+	defer client.Close()
+
+	offset := loadPollOffset(ctx, client) + 1
+
+	for {
+		updates, err := getUpdates(offset, pollTimeoutSeconds)
+		if err != nil {
+			log.Println("getUpdates error:", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+
+		for _, update := range updates {
+			handleUpdate(ctx, client, update)
+			offset = update.UpdateID + 1
+			savePollOffset(ctx, client, update.UpdateID)
+		}
+	}
+}
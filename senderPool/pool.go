@@ -0,0 +1,216 @@
+// Package senderPool routes outbound Telegram Bot API calls across one
+// or more bot tokens, so that forwarding to a growing subscriber list
+// doesn't trip Telegram's 30 msg/sec global or 1 msg/sec per-chat rate
+// limits.
+package senderPool
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	perTokenRatePerSecond = 25
+	perChatRatePerSecond  = 1
+)
+
+// ErrNoCapacity is returned when every token is either suspended (after
+// a 429) or has no spare bucket capacity this instant. Callers should
+// queue the send for a later retry rather than treat it as a permanent
+// failure.
+var ErrNoCapacity = errors.New("senderPool: no token/chat capacity available")
+
+// Pool is safe for concurrent use.
+type Pool struct {
+	mu      sync.Mutex
+	tokens  []*tokenState
+	chatBkt map[int64]*bucket
+	metrics Metrics
+}
+
+type tokenState struct {
+	token       string
+	bucket      *bucket
+	suspendedAt time.Time
+}
+
+// Metrics is a point-in-time snapshot for the /metrics endpoint.
+type Metrics struct {
+	InFlight        int            `json:"in_flight"`
+	TooManyRequests int            `json:"too_many_requests"`
+	SentByToken     map[string]int `json:"sent_by_token"`
+}
+
+// New builds a Pool from one token per bot. Tokens come from the
+// comma-separated BOT_TOKENS env var, falling back to BOT_TOKEN.
+func New(tokens []string) *Pool {
+	p := &Pool{
+		chatBkt: make(map[int64]*bucket),
+		metrics: Metrics{SentByToken: make(map[string]int)},
+	}
+	for _, t := range tokens {
+		if t == "" {
+			continue
+		}
+		p.tokens = append(p.tokens, &tokenState{token: t, bucket: newBucket(perTokenRatePerSecond)})
+	}
+	return p
+}
+
+// Send posts payload to the given Telegram Bot API method on behalf of
+// chatID, routing to the first token whose per-token and per-chat token
+// buckets both have capacity. On HTTP 429 it suspends the chosen token
+// for the server-reported retry_after duration.
+func (p *Pool) Send(method string, chatID int64, payload map[string]interface{}) error {
+	chosen, err := p.reserve(chatID)
+	if err != nil {
+		return err
+	}
+	defer p.release()
+
+	body, _ := json.Marshal(payload)
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/%s", chosen.token, method)
+
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := parseRetryAfter(resp.Body)
+		p.mu.Lock()
+		chosen.suspendedAt = time.Now().Add(retryAfter)
+		p.metrics.TooManyRequests++
+		p.mu.Unlock()
+		return fmt.Errorf("senderPool: token rate limited, retry after %s", retryAfter)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("senderPool: telegram %s failed: %d", method, resp.StatusCode)
+	}
+
+	p.mu.Lock()
+	p.metrics.SentByToken[maskToken(chosen.token)]++
+	p.mu.Unlock()
+
+	return nil
+}
+
+func (p *Pool) reserve(chatID int64) (*tokenState, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	chatBucket, ok := p.chatBkt[chatID]
+	if !ok {
+		chatBucket = newBucket(perChatRatePerSecond)
+		p.chatBkt[chatID] = chatBucket
+	}
+
+	now := time.Now()
+	for _, ts := range p.tokens {
+		if now.Before(ts.suspendedAt) {
+			continue
+		}
+		if !ts.bucket.take() {
+			continue
+		}
+		if !chatBucket.take() {
+			// The chat (not the token) is out of capacity, so give
+			// back the unit we just took — otherwise a burst of sends
+			// to one rate-limited chat would permanently drain this
+			// token's bucket and starve unrelated chats routed to it.
+			ts.bucket.refund()
+			return nil, ErrNoCapacity
+		}
+		p.metrics.InFlight++
+		return ts, nil
+	}
+
+	return nil, ErrNoCapacity
+}
+
+func (p *Pool) release() {
+	p.mu.Lock()
+	p.metrics.InFlight--
+	p.mu.Unlock()
+}
+
+// Snapshot returns a copy of the pool's current metrics.
+func (p *Pool) Snapshot() Metrics {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	snap := Metrics{
+		InFlight:        p.metrics.InFlight,
+		TooManyRequests: p.metrics.TooManyRequests,
+		SentByToken:     make(map[string]int, len(p.metrics.SentByToken)),
+	}
+	for k, v := range p.metrics.SentByToken {
+		snap.SentByToken[k] = v
+	}
+	return snap
+}
+
+func parseRetryAfter(body io.Reader) time.Duration {
+	var parsed struct {
+		Parameters struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.NewDecoder(body).Decode(&parsed); err != nil || parsed.Parameters.RetryAfter <= 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(parsed.Parameters.RetryAfter) * time.Second
+}
+
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return "***"
+	}
+	return token[:4] + "..." + token[len(token)-4:]
+}
+
+// bucket is a simple token bucket: capacity refills continuously at
+// rate tokens/sec, up to rate tokens banked.
+type bucket struct {
+	capacity float64
+	tokens   float64
+	rate     float64
+	last     time.Time
+}
+
+func newBucket(ratePerSecond float64) *bucket {
+	return &bucket{capacity: ratePerSecond, tokens: ratePerSecond, rate: ratePerSecond, last: time.Now()}
+}
+
+func (b *bucket) take() bool {
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// refund gives back a unit consumed by take(), for when the caller
+// backs out of the send it reserved capacity for. It does not advance
+// last, so a pending refill isn't double-counted on the next take().
+func (b *bucket) refund() {
+	b.tokens++
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+}
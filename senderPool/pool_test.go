@@ -0,0 +1,129 @@
+package senderPool
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBucketTakeDepletesThenRefills(t *testing.T) {
+	b := newBucket(2)
+
+	if !b.take() {
+		t.Fatal("expected first take to succeed")
+	}
+	if !b.take() {
+		t.Fatal("expected second take to succeed")
+	}
+	if b.take() {
+		t.Fatal("expected third take to fail, bucket should be empty")
+	}
+
+	// Simulate a second elapsing without sleeping the test.
+	b.last = b.last.Add(-1 * time.Second)
+	if !b.take() {
+		t.Fatal("expected take to succeed after refill")
+	}
+}
+
+func TestBucketTakeCapsAtCapacity(t *testing.T) {
+	b := newBucket(2)
+	b.last = b.last.Add(-time.Hour)
+
+	if !b.take() {
+		t.Fatal("expected take to succeed")
+	}
+	if !b.take() {
+		t.Fatal("expected take to succeed")
+	}
+	if b.take() {
+		t.Fatal("expected take to fail, refill should have capped at capacity")
+	}
+}
+
+func TestReserveSkipsSuspendedToken(t *testing.T) {
+	p := New([]string{"tokenA", "tokenB"})
+	p.tokens[0].suspendedAt = time.Now().Add(time.Hour)
+
+	chosen, err := p.reserve(1)
+	if err != nil {
+		t.Fatalf("reserve returned error: %v", err)
+	}
+	if chosen.token != "tokenB" {
+		t.Fatalf("expected tokenB, got %s", chosen.token)
+	}
+}
+
+func TestReserveNoCapacityWhenAllTokensSuspended(t *testing.T) {
+	p := New([]string{"tokenA", "tokenB"})
+	future := time.Now().Add(time.Hour)
+	p.tokens[0].suspendedAt = future
+	p.tokens[1].suspendedAt = future
+
+	if _, err := p.reserve(1); err != ErrNoCapacity {
+		t.Fatalf("expected ErrNoCapacity, got %v", err)
+	}
+}
+
+func TestReserveNoCapacityWhenChatBucketExhausted(t *testing.T) {
+	p := New([]string{"tokenA"})
+
+	if _, err := p.reserve(1); err != nil {
+		t.Fatalf("first reserve should succeed: %v", err)
+	}
+	if _, err := p.reserve(1); err != ErrNoCapacity {
+		t.Fatalf("expected ErrNoCapacity on second reserve for same chat, got %v", err)
+	}
+}
+
+func TestReserveChatRejectionRefundsTokenBucket(t *testing.T) {
+	p := New([]string{"tokenA"})
+	tokensBefore := p.tokens[0].bucket.tokens
+
+	if _, err := p.reserve(1); err != nil {
+		t.Fatalf("first reserve should succeed: %v", err)
+	}
+	// The chat bucket (capacity 1) is now exhausted, so this reserve is
+	// rejected on the chat check — it must not also burn a second unit
+	// of tokenA's bucket.
+	if _, err := p.reserve(1); err != ErrNoCapacity {
+		t.Fatalf("expected ErrNoCapacity, got %v", err)
+	}
+
+	tokensAfter := p.tokens[0].bucket.tokens
+	if diff := tokensAfter - (tokensBefore - 1); diff < -0.01 || diff > 0.01 {
+		t.Fatalf("expected token bucket to reflect exactly one consumed unit (refunded after chat rejection), got before=%v after=%v", tokensBefore, tokensAfter)
+	}
+
+	// A different chat should still be able to use tokenA's full
+	// remaining capacity — nothing was starved by the rejected reserve.
+	if _, err := p.reserve(2); err != nil {
+		t.Fatalf("reserve for a different chat should succeed: %v", err)
+	}
+}
+
+func TestParseRetryAfterDefaultsWhenMissing(t *testing.T) {
+	got := parseRetryAfter(strings.NewReader(`{}`))
+	if got != 5*time.Second {
+		t.Fatalf("expected default 5s, got %s", got)
+	}
+}
+
+func TestParseRetryAfterParsesBody(t *testing.T) {
+	got := parseRetryAfter(strings.NewReader(`{"parameters":{"retry_after":30}}`))
+	if got != 30*time.Second {
+		t.Fatalf("expected 30s, got %s", got)
+	}
+}
+
+func TestMaskToken(t *testing.T) {
+	if got := maskToken("short"); got != "***" {
+		t.Fatalf("expected *** for short token, got %s", got)
+	}
+
+	long := "123456789:ABCDEFghijklmnop"
+	got := maskToken(long)
+	if !strings.HasPrefix(got, "1234...") {
+		t.Fatalf("expected masked prefix, got %s", got)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestComputeSimHashIdenticalTextsMatch(t *testing.T) {
+	a := computeSimHash("Hiring backend engineers for a fintech startup in Bangalore")
+	b := computeSimHash("Hiring backend engineers for a fintech startup in Bangalore")
+
+	if a != b {
+		t.Fatalf("expected identical text to produce identical SimHash, got %x vs %x", a, b)
+	}
+}
+
+func TestComputeSimHashNearDuplicateIsClose(t *testing.T) {
+	base := "Hiring backend engineers for a fintech startup in Bangalore with five years " +
+		"of experience in Go Java and distributed systems and strong fundamentals in " +
+		"databases caching and message queues apply now through our careers page or " +
+		"write to hr at the company email listed below for more information about the role and team"
+
+	a := computeSimHash(base)
+	b := computeSimHash(base + " thanks")
+
+	if dist := hammingDistance(a, b); dist > simHashThreshold {
+		t.Fatalf("expected near-duplicate text within threshold %d, got distance %d", simHashThreshold, dist)
+	}
+}
+
+func TestComputeSimHashUnrelatedTextIsFar(t *testing.T) {
+	a := computeSimHash("Hiring backend engineers for a fintech startup in Bangalore")
+	b := computeSimHash("Looking for a part time pastry chef in a Mumbai bakery")
+
+	if dist := hammingDistance(a, b); dist <= simHashThreshold {
+		t.Fatalf("expected unrelated text to exceed threshold %d, got distance %d", simHashThreshold, dist)
+	}
+}
+
+func TestComputeSimHashEmptyText(t *testing.T) {
+	if got := computeSimHash(""); got != 0 {
+		t.Fatalf("expected zero SimHash for empty text, got %x", got)
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	cases := []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0b1111, 0b0000, 4},
+		{^uint64(0), 0, 64},
+	}
+
+	for _, c := range cases {
+		if got := hammingDistance(c.a, c.b); got != c.want {
+			t.Errorf("hammingDistance(%b, %b) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestSimHashBandsRoundTrip(t *testing.T) {
+	hash := uint64(0x1234_5678_9ABC_DEF0)
+	bands := simHashBands(hash)
+
+	var reconstructed uint64
+	for i, band := range bands {
+		reconstructed |= uint64(band) << uint(i*16)
+	}
+
+	if reconstructed != hash {
+		t.Fatalf("bands did not reconstruct the original hash: got %x, want %x", reconstructed, hash)
+	}
+}